@@ -0,0 +1,120 @@
+package probe
+
+import (
+	"net/netip"
+	"sort"
+	"sync"
+)
+
+// GroupKeyFunc derives the rolling-stats bucket for an IP, e.g. its ASN or
+// its containing /24. Callers with an ASN database should supply one; the
+// default groups by subnet since this package has no ASN data of its own.
+type GroupKeyFunc func(netip.Addr) string
+
+// DefaultGroupKey buckets by /24 for IPv4 and /48 for IPv6, a reasonable
+// proxy for "same edge" when no ASN lookup is available.
+func DefaultGroupKey(ip netip.Addr) string {
+	bits := 24
+	if ip.Is6() {
+		bits = 48
+	}
+	prefix, err := ip.Prefix(bits)
+	if err != nil {
+		return ip.String()
+	}
+	return prefix.String()
+}
+
+// Ranking summarizes one group's rolling Mbps/latency stats for the final
+// sorted-by-metric report.
+type Ranking struct {
+	Key        string  `json:"key"`
+	Samples    int64   `json:"samples"`
+	P50Mbps    float64 `json:"p50_mbps"`
+	P90Mbps    float64 `json:"p90_mbps"`
+	P99Mbps    float64 `json:"p99_mbps"`
+	P50TotalMS float64 `json:"p50_total_ms"`
+	P90TotalMS float64 `json:"p90_total_ms"`
+	P99TotalMS float64 `json:"p99_total_ms"`
+}
+
+type groupStats struct {
+	mbps    *tdigest
+	totalMS *tdigest
+}
+
+// AggregatingSink maintains per-group (by default per-/24, or per-ASN via a
+// custom GroupKeyFunc) rolling p50/p90/p99 for Mbps and TotalMS using a
+// bounded-memory streaming quantile estimator, so a long Monte-Carlo scan
+// can report "best subnet so far" without holding every sample.
+type AggregatingSink struct {
+	keyFunc     GroupKeyFunc
+	compression float64
+
+	mu     sync.Mutex
+	groups map[string]*groupStats
+}
+
+// NewAggregatingSink builds an AggregatingSink. A nil keyFunc uses
+// DefaultGroupKey.
+func NewAggregatingSink(keyFunc GroupKeyFunc) *AggregatingSink {
+	if keyFunc == nil {
+		keyFunc = DefaultGroupKey
+	}
+	return &AggregatingSink{
+		keyFunc: keyFunc,
+		groups:  make(map[string]*groupStats),
+	}
+}
+
+func (s *AggregatingSink) WriteDownload(r DownloadResult) error {
+	if !r.OK {
+		return nil
+	}
+	key := s.keyFunc(r.IP)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.groups[key]
+	if !ok {
+		g = &groupStats{mbps: newTDigest(0), totalMS: newTDigest(0)}
+		s.groups[key] = g
+	}
+	g.mbps.Add(r.Mbps)
+	g.totalMS.Add(float64(r.TotalMS))
+	return nil
+}
+
+// WriteLatency is a no-op: LatencyResult has no Mbps/TotalMS to aggregate.
+func (s *AggregatingSink) WriteLatency(LatencyResult) error {
+	return nil
+}
+
+func (s *AggregatingSink) Close() error {
+	return nil
+}
+
+// Rankings returns every group's rolling stats, sorted by descending
+// P50Mbps (the "best subnet so far" ordering).
+func (s *AggregatingSink) Rankings() []Ranking {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Ranking, 0, len(s.groups))
+	for key, g := range s.groups {
+		out = append(out, Ranking{
+			Key:        key,
+			Samples:    g.mbps.Count(),
+			P50Mbps:    g.mbps.Quantile(0.50),
+			P90Mbps:    g.mbps.Quantile(0.90),
+			P99Mbps:    g.mbps.Quantile(0.99),
+			P50TotalMS: g.totalMS.Quantile(0.50),
+			P90TotalMS: g.totalMS.Quantile(0.90),
+			P99TotalMS: g.totalMS.Quantile(0.99),
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].P50Mbps > out[j].P50Mbps })
+	return out
+}
@@ -8,11 +8,38 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/netip"
 	"strconv"
 	"time"
 )
 
+// Protocol selects which transport DownloadProber negotiates with the
+// target IP.
+type Protocol int
+
+const (
+	// ProtocolAuto lets the standard transport negotiate HTTP/2 via ALPN
+	// (falling back to HTTP/1.1), which is the historical behavior.
+	ProtocolAuto Protocol = iota
+	ProtocolH1
+	ProtocolH2
+	ProtocolH3
+)
+
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolH1:
+		return "h1"
+	case ProtocolH2:
+		return "h2"
+	case ProtocolH3:
+		return "h3"
+	default:
+		return "auto"
+	}
+}
+
 type DownloadConfig struct {
 	Timeout time.Duration
 	Bytes   int64
@@ -22,6 +49,16 @@ type DownloadConfig struct {
 	// CustomURL indicates the user supplied a custom download URL.
 	// When true, the Path is used as-is (no "?bytes=N" appended).
 	CustomURL bool
+	// Protocol selects h1/h2/h3; zero value (ProtocolAuto) negotiates h1/h2
+	// via ALPN as before. ProtocolH3 dials QUIC directly.
+	Protocol Protocol
+	// Segments, when > 1, splits the download into that many parallel
+	// Range-request GETs over the same transport (so they can use
+	// separate pooled connections), which better reflects the throughput
+	// real multi-connection clients achieve on high-BDP paths. SegmentBytes
+	// sizes each segment; zero divides Bytes evenly across Segments.
+	Segments     int
+	SegmentBytes int64
 }
 
 type DownloadResult struct {
@@ -32,7 +69,25 @@ type DownloadResult struct {
 	Bytes   int64      `json:"bytes"`
 	TotalMS int64      `json:"total_ms"`
 	Mbps    float64    `json:"mbps"`
-	When    time.Time  `json:"when"`
+	// ALPN is the protocol negotiated over TLS (e.g. "h2", "h3"), empty if
+	// no response was received.
+	ALPN string    `json:"alpn,omitempty"`
+	When time.Time `json:"when"`
+
+	// Attempts records every try made by DownloadWithRetry, including this
+	// result itself. Unset for a plain Download call.
+	Attempts []DownloadResult `json:"attempts,omitempty"`
+
+	// Segments records per-segment status when DownloadConfig.Segments > 1.
+	Segments []SegmentResult `json:"segments,omitempty"`
+}
+
+// SegmentResult is one Range-request GET within a multi-segment download.
+type SegmentResult struct {
+	Index  int    `json:"index"`
+	Status int    `json:"status"`
+	Bytes  int64  `json:"bytes"`
+	Error  string `json:"error,omitempty"`
 }
 
 type DownloadProber struct {
@@ -40,52 +95,79 @@ type DownloadProber struct {
 	client *http.Client
 }
 
-func NewDownloadProber(cfg DownloadConfig) *DownloadProber {
-	if cfg.Timeout <= 0 {
-		cfg.Timeout = 45 * time.Second
+// httpTransport builds the RoundTripper for cfg, dispatching to the
+// appropriate protocol-specific constructor.
+func httpTransport(cfg DownloadConfig) http.RoundTripper {
+	if cfg.Protocol == ProtocolH3 {
+		return newH3RoundTripper(cfg)
 	}
-	// Default endpoint needs ?bytes=N in URL; custom URL can use Bytes==0 for "no limit".
-	if cfg.Bytes <= 0 && !cfg.CustomURL {
-		cfg.Bytes = 50_000_000
-	}
-	if cfg.SNI == "" {
-		cfg.SNI = "speed.cloudflare.com"
-	}
-	if cfg.HostName == "" {
-		cfg.HostName = "speed.cloudflare.com"
+
+	nextProtos := []string{"h2", "http/1.1"}
+	switch cfg.Protocol {
+	case ProtocolH1:
+		nextProtos = []string{"http/1.1"}
+	case ProtocolH2:
+		nextProtos = []string{"h2"}
 	}
-	if cfg.Path == "" {
-		cfg.Path = "/__down"
+
+	maxIdlePerHost := 8
+	if cfg.Segments > maxIdlePerHost {
+		// Each segment wants its own pooled connection to the same IP.
+		maxIdlePerHost = cfg.Segments
 	}
 
-	transport := &http.Transport{
+	return &http.Transport{
 		Proxy: nil, // critical: ignore HTTP(S)_PROXY and NO_PROXY env vars
 		DialContext: (&net.Dialer{
 			Timeout:   cfg.Timeout,
 			KeepAlive: 30 * time.Second,
 		}).DialContext,
-		ForceAttemptHTTP2:     true,
+		ForceAttemptHTTP2:     cfg.Protocol != ProtocolH1,
 		MaxIdleConns:          64,
-		MaxIdleConnsPerHost:   8,
+		MaxIdleConnsPerHost:   maxIdlePerHost,
 		IdleConnTimeout:       30 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ResponseHeaderTimeout: 20 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
 		TLSClientConfig: &tls.Config{
 			ServerName: cfg.SNI,
+			NextProtos: nextProtos,
 		},
 	}
+}
+
+func NewDownloadProber(cfg DownloadConfig) *DownloadProber {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 45 * time.Second
+	}
+	// Default endpoint needs ?bytes=N in URL; custom URL can use Bytes==0 for "no limit".
+	if cfg.Bytes <= 0 && !cfg.CustomURL {
+		cfg.Bytes = 50_000_000
+	}
+	if cfg.SNI == "" {
+		cfg.SNI = "speed.cloudflare.com"
+	}
+	if cfg.HostName == "" {
+		cfg.HostName = "speed.cloudflare.com"
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/__down"
+	}
 
 	return &DownloadProber{
 		cfg: cfg,
 		client: &http.Client{
-			Transport: transport,
+			Transport: httpTransport(cfg),
 			Timeout:   cfg.Timeout,
 		},
 	}
 }
 
 func (p *DownloadProber) Download(ctx context.Context, ip netip.Addr) DownloadResult {
+	if p.cfg.Segments > 1 {
+		return p.downloadSegmented(ctx, ip)
+	}
+
 	start := time.Now()
 	out := DownloadResult{
 		IP:   ip,
@@ -106,7 +188,16 @@ func (p *DownloadProber) Download(ctx context.Context, ip netip.Addr) DownloadRe
 		url = "https://" + host + p.cfg.Path + "?bytes=" + strconv.FormatInt(p.cfg.Bytes, 10)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	var alpn string
+	traceCtx := httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err == nil {
+				alpn = state.NegotiatedProtocol
+			}
+		},
+	})
+
+	req, err := http.NewRequestWithContext(traceCtx, http.MethodGet, url, nil)
 	if err != nil {
 		out.Error = err.Error()
 		out.TotalMS = time.Since(start).Milliseconds()
@@ -128,6 +219,12 @@ func (p *DownloadProber) Download(ctx context.Context, ip netip.Addr) DownloadRe
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if p.cfg.Protocol == ProtocolH3 {
+		// quic-go's http3 RoundTripper doesn't drive the TLS httptrace
+		// hooks, so the negotiated ALPN is implied by the protocol choice.
+		alpn = "h3"
+	}
+	out.ALPN = alpn
 	out.Status = resp.StatusCode
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		out.Error = fmt.Sprintf("http_status_%d", resp.StatusCode)
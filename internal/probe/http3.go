@@ -0,0 +1,86 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// newH3RoundTripper builds an http3.RoundTripper for cfg. The URL passed to
+// Download already carries the target's literal IP as the authority, so the
+// custom Dial below only needs to pin the QUIC handshake to UDP and apply
+// the configured SNI/HostName; no DNS resolution ever happens.
+//
+// Overriding Dial means quic-go never takes ownership of the UDP socket it
+// hands back (http3.RoundTripper only closes a *self-created* transport,
+// and quic.DialEarly itself never closes a conn it didn't create), so we
+// have to close it ourselves: closingEarlyConnection closes the socket
+// alongside the QUIC connection, and DownloadProber.Close backstops any
+// socket that never gets an explicit CloseWithError (e.g. a cached
+// per-host connection that's simply never used again).
+func newH3RoundTripper(cfg DownloadConfig) *http3.RoundTripper {
+	tlsConf := &tls.Config{
+		ServerName: cfg.SNI,
+		NextProtos: []string{http3.NextProtoH3},
+	}
+
+	dial := func(ctx context.Context, addr string, tlsCfg *tls.Config, quicCfg *quic.Config) (quic.EarlyConnection, error) {
+		udpAddr, err := net.ResolveUDPAddr("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		udpConn, err := net.ListenUDP("udp", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := quic.DialEarly(ctx, udpConn, udpAddr, tlsCfg, quicCfg)
+		if err != nil {
+			_ = udpConn.Close()
+			return nil, err
+		}
+		return &closingEarlyConnection{EarlyConnection: conn, udpConn: udpConn}, nil
+	}
+
+	return &http3.RoundTripper{
+		TLSClientConfig: tlsConf,
+		QuicConfig:      &quic.Config{HandshakeIdleTimeout: cfg.Timeout},
+		Dial:            dial,
+	}
+}
+
+// closingEarlyConnection closes its UDP socket the first time the
+// connection is closed, so the fd isn't left open once quic-go is done
+// with it.
+type closingEarlyConnection struct {
+	quic.EarlyConnection
+	udpConn *net.UDPConn
+	once    sync.Once
+}
+
+func (c *closingEarlyConnection) CloseWithError(code quic.ApplicationErrorCode, reason string) error {
+	err := c.EarlyConnection.CloseWithError(code, reason)
+	c.once.Do(func() { _ = c.udpConn.Close() })
+	return err
+}
+
+// Close releases resources held by p's transport. For HTTP/3 this closes
+// every cached per-host QUIC connection (and, via closingEarlyConnection,
+// the UDP sockets Dial opened for them); for h1/h2 it closes idle pooled
+// TCP connections. Callers that build many DownloadProbers should call
+// Close once a prober is no longer needed; Runner does not close the
+// prober it is given.
+func (p *DownloadProber) Close() error {
+	switch t := p.client.Transport.(type) {
+	case *http3.RoundTripper:
+		return t.Close()
+	case *http.Transport:
+		t.CloseIdleConnections()
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/netip"
+	"time"
+)
+
+// LatencyConfig controls how LatencyProber measures a single IP.
+type LatencyConfig struct {
+	Timeout  time.Duration
+	SNI      string
+	HostName string
+	Path     string
+	// Samples is the number of sequential probes averaged (and used to
+	// compute jitter) per Probe call.
+	Samples int
+}
+
+// LatencyResult holds per-phase timing for one IP, averaged across the
+// successful ones of LatencyConfig.Samples attempts.
+type LatencyResult struct {
+	IP netip.Addr `json:"ip"`
+
+	ConnectMS      float64 `json:"connect_ms"`
+	TLSHandshakeMS float64 `json:"tls_handshake_ms"`
+	TTFBMS         float64 `json:"ttfb_ms"`
+
+	// JitterMS is the stddev of TTFB across samples.
+	JitterMS float64 `json:"jitter_ms"`
+	// SuccessRatio is successful_samples / Samples.
+	SuccessRatio float64 `json:"success_ratio"`
+
+	Samples int `json:"samples"`
+	When    time.Time `json:"when"`
+}
+
+// LatencyProber measures TCP-connect, TLS-handshake, and time-to-first-byte
+// latency for an IP, without downloading a payload body. It's meant as a
+// cheap pre-filter before DownloadProber spends bandwidth on slow IPs.
+type LatencyProber struct {
+	cfg    LatencyConfig
+	client *http.Client
+}
+
+func NewLatencyProber(cfg LatencyConfig) *LatencyProber {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.SNI == "" {
+		cfg.SNI = "speed.cloudflare.com"
+	}
+	if cfg.HostName == "" {
+		cfg.HostName = "speed.cloudflare.com"
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/__down?bytes=0"
+	}
+	if cfg.Samples <= 0 {
+		cfg.Samples = 3
+	}
+
+	transport := &http.Transport{
+		Proxy: nil, // critical: ignore HTTP(S)_PROXY and NO_PROXY env vars
+		DialContext: (&net.Dialer{
+			Timeout:   cfg.Timeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		DisableKeepAlives:     true, // each sample needs its own connect/TLS phases
+		TLSHandshakeTimeout:   cfg.Timeout,
+		ResponseHeaderTimeout: cfg.Timeout,
+		TLSClientConfig: &tls.Config{
+			ServerName: cfg.SNI,
+		},
+	}
+
+	return &LatencyProber{
+		cfg: cfg,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.Timeout,
+		},
+	}
+}
+
+// phaseTimes is populated by an httptrace.ClientTrace during a single probe.
+type phaseTimes struct {
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+}
+
+// Probe runs cfg.Samples sequential requests against ip and returns the
+// averaged per-phase latency plus TTFB jitter and success ratio.
+func (p *LatencyProber) Probe(ctx context.Context, ip netip.Addr) LatencyResult {
+	out := LatencyResult{
+		IP:      ip,
+		Samples: p.cfg.Samples,
+		When:    time.Now(),
+	}
+
+	host := ip.String()
+	if ip.Is6() {
+		host = "[" + host + "]"
+	}
+	url := "https://" + host + p.cfg.Path
+
+	var connectSum, tlsSum, ttfbSum float64
+	var ttfbSamples []float64
+	var ok int
+
+	for i := 0; i < p.cfg.Samples; i++ {
+		pt := &phaseTimes{}
+		trace := &httptrace.ClientTrace{
+			ConnectStart: func(network, addr string) { pt.connectStart = time.Now() },
+			ConnectDone: func(network, addr string, err error) {
+				if err == nil {
+					pt.connectDone = time.Now()
+				}
+			},
+			TLSHandshakeStart: func() { pt.tlsStart = time.Now() },
+			TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+				if err == nil {
+					pt.tlsDone = time.Now()
+				}
+			},
+			GotFirstResponseByte: func() { pt.firstByte = time.Now() },
+		}
+
+		reqCtx := httptrace.WithClientTrace(ctx, trace)
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodHead, url, nil)
+		if err != nil {
+			continue
+		}
+		req.Host = p.cfg.HostName
+
+		start := time.Now()
+		resp, err := p.client.Do(req)
+		if err != nil {
+			continue
+		}
+		_ = resp.Body.Close()
+
+		if !pt.connectDone.IsZero() {
+			connectSum += pt.connectDone.Sub(pt.connectStart).Seconds() * 1000
+		}
+		if !pt.tlsDone.IsZero() {
+			tlsSum += pt.tlsDone.Sub(pt.tlsStart).Seconds() * 1000
+		}
+		if !pt.firstByte.IsZero() {
+			ttfb := pt.firstByte.Sub(start).Seconds() * 1000
+			ttfbSum += ttfb
+			ttfbSamples = append(ttfbSamples, ttfb)
+		}
+		ok++
+	}
+
+	if ok > 0 {
+		out.ConnectMS = connectSum / float64(ok)
+		out.TLSHandshakeMS = tlsSum / float64(ok)
+		out.TTFBMS = ttfbSum / float64(ok)
+	}
+	out.JitterMS = stddev(ttfbSamples)
+	out.SuccessRatio = float64(ok) / float64(p.cfg.Samples)
+
+	return out
+}
+
+// stddev returns the population standard deviation of samples, or 0 for
+// fewer than two samples.
+func stddev(samples []float64) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += s
+	}
+	mean := sum / float64(len(samples))
+
+	var sq float64
+	for _, s := range samples {
+		d := s - mean
+		sq += d * d
+	}
+	return math.Sqrt(sq / float64(len(samples)))
+}
@@ -0,0 +1,154 @@
+package probe
+
+import (
+	"math"
+	"sort"
+)
+
+// tdigest is a simplified t-digest: a one-pass, bounded-memory quantile
+// estimator. Samples are merged into nearby centroids, with each
+// centroid's maximum weight scaled down near the tails (q near 0 or 1) so
+// extreme quantiles stay accurate even after millions of samples. This is
+// what lets AggregatingSink report p50/p90/p99 for a long-running scan
+// without holding every sample in memory.
+type tdigest struct {
+	compression float64
+	centroids   []centroid // kept sorted by mean
+	count       float64
+}
+
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// newTDigest returns a tdigest that compresses whenever it holds more than
+// compression*2 centroids. Compression does not always bring the count back
+// under that threshold: it grows slowly with the number of samples (a few
+// hundred at the default). Higher compression trades memory for accuracy. Zero uses a default of
+// 100, accurate enough for ranking IP subnets by rough percentile.
+func newTDigest(compression float64) *tdigest {
+	if compression <= 0 {
+		compression = 100
+	}
+	return &tdigest{compression: compression}
+}
+
+// Add merges x into the digest with unit weight.
+func (t *tdigest) Add(x float64) {
+	if len(t.centroids) == 0 {
+		t.centroids = []centroid{{mean: x, weight: 1}}
+		t.count = 1
+		return
+	}
+
+	idx := sort.Search(len(t.centroids), func(i int) bool { return t.centroids[i].mean >= x })
+	best, bestDist := -1, math.Inf(1)
+	for _, j := range [2]int{idx - 1, idx} {
+		if j < 0 || j >= len(t.centroids) {
+			continue
+		}
+		if d := math.Abs(t.centroids[j].mean - x); d < bestDist {
+			best, bestDist = j, d
+		}
+	}
+
+	if best >= 0 {
+		c := t.centroids[best]
+		q := t.quantileOfIndex(best)
+		maxWeight := math.Max(1, 4*t.count*q*(1-q)/t.compression)
+		if c.weight+1 <= maxWeight {
+			newWeight := c.weight + 1
+			t.centroids[best] = centroid{
+				mean:   c.mean + (x-c.mean)/newWeight,
+				weight: newWeight,
+			}
+			t.count++
+			return
+		}
+	}
+
+	t.centroids = append(t.centroids, centroid{})
+	copy(t.centroids[idx+1:], t.centroids[idx:])
+	t.centroids[idx] = centroid{mean: x, weight: 1}
+	t.count++
+
+	if len(t.centroids) > int(t.compression)*2 {
+		t.compress()
+	}
+}
+
+// quantileOfIndex estimates the quantile at the midpoint of centroid i,
+// used to decide how much that centroid is allowed to grow.
+func (t *tdigest) quantileOfIndex(i int) float64 {
+	var cum float64
+	for j := 0; j < i; j++ {
+		cum += t.centroids[j].weight
+	}
+	cum += t.centroids[i].weight / 2
+	return cum / t.count
+}
+
+// compress merges adjacent centroids in place, bounding the digest's size
+// back down after unbounded growth from Add. It scales each merge's max
+// weight off the digest's existing t.count (which is left untouched), not
+// a freshly reset counter — replaying samples through Add with t.count
+// reset to 0 made maxWeight collapse to ~1, which reproduced the same
+// centroid count every time and recursed into compress forever.
+func (t *tdigest) compress() {
+	old := t.centroids
+	if len(old) == 0 {
+		return
+	}
+
+	merged := make([]centroid, 0, len(old))
+	cur := old[0]
+	var cumBefore float64
+
+	for _, c := range old[1:] {
+		q := (cumBefore + cur.weight/2) / t.count
+		maxWeight := math.Max(1, 4*t.count*q*(1-q)/t.compression)
+
+		if cur.weight+c.weight <= maxWeight {
+			newWeight := cur.weight + c.weight
+			cur = centroid{
+				mean:   cur.mean + (c.mean-cur.mean)*c.weight/newWeight,
+				weight: newWeight,
+			}
+			continue
+		}
+
+		merged = append(merged, cur)
+		cumBefore += cur.weight
+		cur = c
+	}
+	merged = append(merged, cur)
+
+	t.centroids = merged
+}
+
+// Quantile returns the approximate value at quantile q (0..1). Returns 0
+// for an empty digest.
+func (t *tdigest) Quantile(q float64) float64 {
+	if len(t.centroids) == 0 {
+		return 0
+	}
+	if len(t.centroids) == 1 {
+		return t.centroids[0].mean
+	}
+
+	target := q * t.count
+	var cum float64
+	for i, c := range t.centroids {
+		if cum+c.weight >= target || i == len(t.centroids)-1 {
+			return c.mean
+		}
+		cum += c.weight
+	}
+	return t.centroids[len(t.centroids)-1].mean
+}
+
+// Count returns the number of samples added.
+func (t *tdigest) Count() int64 {
+	return int64(t.count)
+}
@@ -0,0 +1,47 @@
+package probe
+
+import (
+	"net/netip"
+	"testing"
+)
+
+// TestTDigestCompressDoesNotRecurseForever guards against the compress()
+// regression where resetting t.count before replaying samples caused
+// maxWeight to collapse and compress() to recurse without bound.
+func TestTDigestCompressDoesNotRecurseForever(t *testing.T) {
+	td := newTDigest(0)
+	for i := 0; i < 5000; i++ {
+		td.Add(float64(i))
+	}
+
+	if got := td.Count(); got != 5000 {
+		t.Fatalf("Count() = %d, want 5000", got)
+	}
+
+	p50 := td.Quantile(0.5)
+	if p50 < 2000 || p50 > 3000 {
+		t.Fatalf("Quantile(0.5) = %v, want roughly 2500", p50)
+	}
+}
+
+func TestAggregatingSinkWriteDownloadPastCompressionThreshold(t *testing.T) {
+	sink := NewAggregatingSink(nil)
+	for i := 0; i < 500; i++ {
+		err := sink.WriteDownload(DownloadResult{
+			IP:   netip.MustParseAddr("203.0.113.1"),
+			OK:   true,
+			Mbps: float64(i),
+		})
+		if err != nil {
+			t.Fatalf("WriteDownload: %v", err)
+		}
+	}
+
+	rankings := sink.Rankings()
+	if len(rankings) != 1 {
+		t.Fatalf("len(rankings) = %d, want 1", len(rankings))
+	}
+	if rankings[0].Samples != 500 {
+		t.Fatalf("Samples = %d, want 500", rankings[0].Samples)
+	}
+}
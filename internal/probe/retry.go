@@ -0,0 +1,67 @@
+package probe
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// RetryConfig controls DownloadWithRetry's attempt/backoff behavior.
+type RetryConfig struct {
+	// Attempts is the maximum number of tries, including the first. Zero
+	// is treated as 1 (no retry).
+	Attempts uint
+	// Cooldown is slept between attempts (not after the last one).
+	Cooldown time.Duration
+}
+
+// DownloadWithRetry calls Download up to rc.Attempts times, sleeping
+// rc.Cooldown between tries, and stops early on a permanent error (4xx,
+// TLS cert-name mismatch) since retrying those can't change the outcome.
+// The returned DownloadResult is the last attempt, with every attempt
+// (including itself) recorded in its Attempts field.
+func (p *DownloadProber) DownloadWithRetry(ctx context.Context, ip netip.Addr, rc RetryConfig) DownloadResult {
+	attempts := rc.Attempts
+	if attempts == 0 {
+		attempts = 1
+	}
+
+	var history []DownloadResult
+	var last DownloadResult
+
+	for i := uint(0); i < attempts; i++ {
+		last = p.Download(ctx, ip)
+		history = append(history, last)
+
+		if last.OK || isPermanentError(last) {
+			break
+		}
+		if i+1 >= attempts || rc.Cooldown <= 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			i = attempts // stop retrying once the caller gives up
+		case <-time.After(rc.Cooldown):
+		}
+	}
+
+	last.Attempts = history
+	return last
+}
+
+// isPermanentError reports whether out reflects an error that will not be
+// fixed by retrying: a 4xx response, or a TLS certificate name mismatch.
+// Everything else (dial timeout, TLS handshake reset, context deadline,
+// 5xx) is treated as transient and worth another attempt.
+func isPermanentError(out DownloadResult) bool {
+	if out.Status >= 400 && out.Status < 500 {
+		return true
+	}
+	if out.Error == "" {
+		return false
+	}
+	return strings.Contains(out.Error, "certificate is valid for") ||
+		strings.Contains(out.Error, "certificate is not valid for any names")
+}
@@ -0,0 +1,256 @@
+package probe
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// RunnerConfig bounds how hard Runner hammers targets while fanning out
+// probes across a worker pool.
+type RunnerConfig struct {
+	// Concurrency is the total number of in-flight probes across all IPs.
+	// Zero defaults to 8.
+	Concurrency int
+	// RPS caps the aggregate request rate across the whole run via a token
+	// bucket. Zero (or negative) means unlimited.
+	RPS float64
+	// PerHostConcurrency caps in-flight probes against a single IP, so a
+	// retry burst or a repeated target doesn't hammer one edge. Zero
+	// defaults to 1.
+	PerHostConcurrency int
+}
+
+// Progress is a point-in-time snapshot of a Runner's throughput, suitable
+// for rendering in a TUI.
+type Progress struct {
+	InFlight  int
+	Completed int
+	OK        int
+	Failed    int
+	AvgMbps   float64
+}
+
+// Runner fans Download and Probe (latency) calls for many IPs out across a
+// bounded worker pool, rate-limited and per-host-capped, and reports live
+// progress. It's the first-class replacement for callers hand-rolling
+// goroutine plumbing around DownloadProber.
+type Runner struct {
+	downloader *DownloadProber
+	latency    *LatencyProber
+	cfg        RunnerConfig
+
+	mu       sync.Mutex
+	progress Progress
+	mbpsSum  float64
+
+	hostSem sync.Map // netip.Addr -> chan struct{}
+}
+
+// NewRunner builds a Runner. Either prober may be nil if the corresponding
+// Run method won't be used.
+func NewRunner(downloader *DownloadProber, latency *LatencyProber, cfg RunnerConfig) *Runner {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 8
+	}
+	if cfg.PerHostConcurrency <= 0 {
+		cfg.PerHostConcurrency = 1
+	}
+	return &Runner{downloader: downloader, latency: latency, cfg: cfg}
+}
+
+// Progress returns a snapshot of the run's live counters.
+func (r *Runner) Progress() Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := r.progress
+	if p.OK > 0 {
+		p.AvgMbps = r.mbpsSum / float64(p.OK)
+	}
+	return p
+}
+
+// AddrChan adapts a slice of addresses into the channel form Run* expects.
+func AddrChan(ips []netip.Addr) <-chan netip.Addr {
+	ch := make(chan netip.Addr, len(ips))
+	for _, ip := range ips {
+		ch <- ip
+	}
+	close(ch)
+	return ch
+}
+
+// RunDownloads fans Download calls for ips out across the worker pool and
+// streams results as they complete. The returned channel is closed once
+// ips is drained and every in-flight probe has finished.
+func (r *Runner) RunDownloads(ctx context.Context, ips <-chan netip.Addr) <-chan DownloadResult {
+	out := make(chan DownloadResult)
+	runCtx, cancel := context.WithCancel(ctx)
+	limiter := newTokenBucket(runCtx, r.cfg.RPS)
+	sem := make(chan struct{}, r.cfg.Concurrency)
+
+	go func() {
+		defer close(out)
+		defer cancel() // stop the token-bucket ticker once this run is done
+		var wg sync.WaitGroup
+		for ip := range ips {
+			ip := ip
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				hostSem := r.hostSemaphore(ip)
+				select {
+				case hostSem <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				defer func() { <-hostSem }()
+
+				if err := limiter.Wait(ctx); err != nil {
+					return
+				}
+
+				r.trackStart()
+				res := r.downloader.Download(ctx, ip)
+				r.trackDone(res.OK, res.Mbps)
+
+				select {
+				case out <- res:
+				case <-ctx.Done():
+				}
+			}()
+		}
+		wg.Wait()
+	}()
+
+	return out
+}
+
+// RunLatency is RunDownloads' sibling for LatencyProber.Probe.
+func (r *Runner) RunLatency(ctx context.Context, ips <-chan netip.Addr) <-chan LatencyResult {
+	out := make(chan LatencyResult)
+	runCtx, cancel := context.WithCancel(ctx)
+	limiter := newTokenBucket(runCtx, r.cfg.RPS)
+	sem := make(chan struct{}, r.cfg.Concurrency)
+
+	go func() {
+		defer close(out)
+		defer cancel() // stop the token-bucket ticker once this run is done
+		var wg sync.WaitGroup
+		for ip := range ips {
+			ip := ip
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				hostSem := r.hostSemaphore(ip)
+				select {
+				case hostSem <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				defer func() { <-hostSem }()
+
+				if err := limiter.Wait(ctx); err != nil {
+					return
+				}
+
+				r.trackStart()
+				res := r.latency.Probe(ctx, ip)
+				r.trackDone(res.SuccessRatio > 0, 0)
+
+				select {
+				case out <- res:
+				case <-ctx.Done():
+				}
+			}()
+		}
+		wg.Wait()
+	}()
+
+	return out
+}
+
+func (r *Runner) hostSemaphore(ip netip.Addr) chan struct{} {
+	sem, _ := r.hostSem.LoadOrStore(ip, make(chan struct{}, r.cfg.PerHostConcurrency))
+	return sem.(chan struct{})
+}
+
+func (r *Runner) trackStart() {
+	r.mu.Lock()
+	r.progress.InFlight++
+	r.mu.Unlock()
+}
+
+func (r *Runner) trackDone(ok bool, mbps float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.progress.InFlight--
+	r.progress.Completed++
+	if ok {
+		r.progress.OK++
+		r.mbpsSum += mbps
+	} else {
+		r.progress.Failed++
+	}
+}
+
+// tokenBucket is a minimal token-bucket rate limiter: one token is minted
+// every 1/rps and buffered (capacity 1), so bursts above rps still get
+// smoothed out over time rather than admitted all at once.
+type tokenBucket struct {
+	tokens chan struct{}
+}
+
+// newTokenBucket returns nil for rps <= 0, meaning "unlimited" — callers
+// must use (*tokenBucket).Wait, which treats a nil receiver as a no-op.
+// The minting goroutine exits when ctx is done, so callers must cancel ctx
+// once the run that owns this bucket finishes.
+func newTokenBucket(ctx context.Context, rps float64) *tokenBucket {
+	if rps <= 0 {
+		return nil
+	}
+
+	tb := &tokenBucket{tokens: make(chan struct{}, 1)}
+	interval := time.Duration(float64(time.Second) / rps)
+	if interval <= 0 {
+		interval = time.Nanosecond
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				select {
+				case tb.tokens <- struct{}{}:
+				default:
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return tb
+}
+
+func (tb *tokenBucket) Wait(ctx context.Context) error {
+	if tb == nil {
+		return nil
+	}
+	select {
+	case <-tb.tokens:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
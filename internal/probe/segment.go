@@ -0,0 +1,156 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// downloadSegmented implements Download for DownloadConfig.Segments > 1: it
+// issues Segments parallel Range-request GETs over the shared transport
+// (so they can land on separate pooled connections to the same IP) and
+// aggregates them into a single DownloadResult, with wall-clock measured
+// from the first byte of any segment to the last byte of all of them —
+// this is the throughput a real multi-connection client actually gets.
+func (p *DownloadProber) downloadSegmented(ctx context.Context, ip netip.Addr) DownloadResult {
+	start := time.Now()
+	out := DownloadResult{IP: ip, When: start}
+
+	host := ip.String()
+	if ip.Is6() {
+		host = "[" + host + "]"
+	}
+
+	total := p.cfg.Bytes
+	segBytes := p.cfg.SegmentBytes
+	if segBytes <= 0 {
+		segBytes = total / int64(p.cfg.Segments)
+	}
+	if segBytes <= 0 {
+		out.Error = "segment_bytes_must_be_positive"
+		out.TotalMS = time.Since(start).Milliseconds()
+		return out
+	}
+
+	var url string
+	if p.cfg.CustomURL {
+		url = "https://" + host + p.cfg.Path
+	} else {
+		url = "https://" + host + p.cfg.Path + "?bytes=" + strconv.FormatInt(segBytes*int64(p.cfg.Segments), 10)
+	}
+
+	segments := make([]SegmentResult, p.cfg.Segments)
+
+	var mu sync.Mutex
+	var firstByte, lastByte time.Time
+	var totalBytes int64
+	var alpn string
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.cfg.Segments; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			rangeStart := int64(i) * segBytes
+			rangeEnd := rangeStart + segBytes - 1
+
+			seg := SegmentResult{Index: i}
+			defer func() {
+				mu.Lock()
+				segments[i] = seg
+				mu.Unlock()
+			}()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				seg.Error = err.Error()
+				return
+			}
+			req.Host = p.cfg.HostName
+			req.Header.Set("User-Agent", "mcis/0.1")
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", rangeStart, rangeEnd))
+
+			resp, err := p.client.Do(req)
+			if err != nil {
+				seg.Error = err.Error()
+				return
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			respAt := time.Now()
+			mu.Lock()
+			if firstByte.IsZero() || respAt.Before(firstByte) {
+				firstByte = respAt
+			}
+			if alpn == "" && resp.TLS != nil {
+				alpn = resp.TLS.NegotiatedProtocol
+			}
+			mu.Unlock()
+
+			seg.Status = resp.StatusCode
+			if resp.StatusCode != http.StatusPartialContent {
+				// Anything other than 206 fails the segment; a 200 in
+				// particular means the target ignored our Range header
+				// and is about to send the full body instead of this
+				// segment's slice.
+				seg.Error = fmt.Sprintf("http_status_%d", resp.StatusCode)
+				return
+			}
+
+			// Backstop against a target that claimed 206 but still sends
+			// more than segBytes: never read further than our slice.
+			n, err := io.CopyN(io.Discard, resp.Body, segBytes)
+			seg.Bytes = n
+			now := time.Now()
+
+			mu.Lock()
+			if now.After(lastByte) {
+				lastByte = now
+			}
+			totalBytes += n
+			mu.Unlock()
+
+			if err != nil && err != io.EOF {
+				seg.Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	out.TotalMS = time.Since(start).Milliseconds()
+	out.Bytes = totalBytes
+	out.Segments = segments
+	out.ALPN = alpn
+	if p.cfg.Protocol == ProtocolH3 {
+		out.ALPN = "h3"
+	}
+
+	if elapsed := lastByte.Sub(firstByte); elapsed > 0 {
+		out.Mbps = (float64(totalBytes) * 8) / elapsed.Seconds() / 1e6
+	}
+
+	ok := totalBytes > 0
+	for _, seg := range segments {
+		if seg.Error != "" {
+			ok = false
+			// Carry the failing segment's status and error so the result
+			// classifies like a plain Download (see isPermanentError).
+			out.Status = seg.Status
+			out.Error = "segment_failed: " + seg.Error
+			break
+		}
+	}
+	out.OK = ok
+	if !ok && out.Error == "" {
+		out.Error = "segment_failed"
+	}
+
+	return out
+}
@@ -0,0 +1,107 @@
+package probe
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ResultSink consumes probe results as a scan runs. Implementations decide
+// how (or whether) to persist each result; Close flushes and releases any
+// underlying resources.
+type ResultSink interface {
+	WriteDownload(DownloadResult) error
+	WriteLatency(LatencyResult) error
+	Close() error
+}
+
+// JSONLSink writes one JSON object per line, tagged with a "kind" field so
+// a single file can hold both download and latency results.
+type JSONLSink struct {
+	w   io.Writer
+	enc *json.Encoder
+}
+
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{w: w, enc: json.NewEncoder(w)}
+}
+
+type jsonlRecord struct {
+	Kind     string          `json:"kind"`
+	Download *DownloadResult `json:"download,omitempty"`
+	Latency  *LatencyResult  `json:"latency,omitempty"`
+}
+
+func (s *JSONLSink) WriteDownload(r DownloadResult) error {
+	return s.enc.Encode(jsonlRecord{Kind: "download", Download: &r})
+}
+
+func (s *JSONLSink) WriteLatency(r LatencyResult) error {
+	return s.enc.Encode(jsonlRecord{Kind: "latency", Latency: &r})
+}
+
+func (s *JSONLSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// CSVSink writes DownloadResult rows to a fixed-column CSV. It does not
+// support LatencyResult, which has an incompatible schema; WriteLatency
+// returns an error rather than silently dropping data.
+type CSVSink struct {
+	w           *csv.Writer
+	closer      io.Closer
+	wroteHeader bool
+}
+
+var csvHeader = []string{"ip", "ok", "status", "error", "bytes", "total_ms", "mbps", "alpn", "when"}
+
+func NewCSVSink(w io.Writer) *CSVSink {
+	closer, _ := w.(io.Closer)
+	return &CSVSink{w: csv.NewWriter(w), closer: closer}
+}
+
+func (s *CSVSink) WriteDownload(r DownloadResult) error {
+	if !s.wroteHeader {
+		if err := s.w.Write(csvHeader); err != nil {
+			return err
+		}
+		s.wroteHeader = true
+	}
+
+	row := []string{
+		r.IP.String(),
+		strconv.FormatBool(r.OK),
+		strconv.Itoa(r.Status),
+		r.Error,
+		strconv.FormatInt(r.Bytes, 10),
+		strconv.FormatInt(r.TotalMS, 10),
+		strconv.FormatFloat(r.Mbps, 'f', 3, 64),
+		r.ALPN,
+		r.When.Format("2006-01-02T15:04:05.000Z07:00"),
+	}
+	if err := s.w.Write(row); err != nil {
+		return err
+	}
+	s.w.Flush()
+	return s.w.Error()
+}
+
+func (s *CSVSink) WriteLatency(LatencyResult) error {
+	return fmt.Errorf("probe: CSVSink does not support LatencyResult")
+}
+
+func (s *CSVSink) Close() error {
+	s.w.Flush()
+	if err := s.w.Error(); err != nil {
+		return err
+	}
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}